@@ -0,0 +1,159 @@
+package gopop3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// apopTimestampRe 用于从问候语中提取 APOP 时间戳，例如
+// "+OK POP3 server ready <1896.697170952@dbc.mtview.ca.us>" 中的
+// "<1896.697170952@dbc.mtview.ca.us>"。
+var apopTimestampRe = regexp.MustCompile(`<[^>]+>`)
+
+// Authenticator 是一种可插拔的登录机制，供 Conn.AuthWith 使用。
+// 除明文 USER/PASS 外的登录方式（APOP、SASL CRAM-MD5/PLAIN/XOAUTH2 等）
+// 均以 Authenticator 的形式提供。
+type Authenticator interface {
+	Authenticate(c *Conn) error
+}
+
+// AuthWith 使用给定的 Authenticator 完成身份验证。
+func (c *Conn) AuthWith(a Authenticator) error {
+	return a.Authenticate(c)
+}
+
+// ChallengeCmd 类似 Cmd，但会识别 `+ <base64>` 形式的服务器内联质询（challenge），
+// 并将其作为质询而非错误返回，isChallenge 为 true 时 buf 中是质询的原始内容
+// （已去除 "+ " 前缀，未解码）。用于实现需要多次往返的 SASL 机制。
+func (c *Conn) ChallengeCmd(cmd string, args ...interface{}) (buf *bytes.Buffer, isChallenge bool, err error) {
+	var cmdLine string
+	if len(args) > 0 {
+		format := " " + strings.TrimRight(strings.Repeat("%v ", len(args)), " ")
+		cmdLine = fmt.Sprintf(cmd+format, args...)
+	} else {
+		cmdLine = cmd
+	}
+	if err = c.Send(cmdLine); err != nil {
+		return nil, false, err
+	}
+
+	if err = c.applyReadDeadline(); err != nil {
+		return nil, false, err
+	}
+
+	b, _, err := c.r.ReadLine()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if bytes.HasPrefix(b, []byte("+ ")) {
+		return bytes.NewBuffer(bytes.TrimPrefix(b, []byte("+ "))), true, nil
+	}
+
+	r, err := parseResp(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return bytes.NewBuffer(r), false, nil
+}
+
+// APOPAuthenticator 使用服务器问候语中的时间戳实现 APOP 登录（RFC 1939 §7）。
+type APOPAuthenticator struct {
+	User     string
+	Password string
+}
+
+func (a APOPAuthenticator) Authenticate(c *Conn) error {
+	if c.apopTimestamp == "" {
+		return fmt.Errorf("server did not advertise an APOP timestamp in its greeting")
+	}
+
+	h := md5.New()
+	io.WriteString(h, c.apopTimestamp+a.Password)
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	_, err := c.Cmd("APOP", false, a.User, digest)
+	return err
+}
+
+// CRAMMD5Authenticator 实现 SASL CRAM-MD5 登录（RFC 2195）。
+type CRAMMD5Authenticator struct {
+	User     string
+	Password string
+}
+
+func (a CRAMMD5Authenticator) Authenticate(c *Conn) error {
+	buf, isChallenge, err := c.ChallengeCmd("AUTH CRAM-MD5")
+	if err != nil {
+		return err
+	}
+	if !isChallenge {
+		return fmt.Errorf("server did not issue a CRAM-MD5 challenge")
+	}
+
+	challenge, err := base64.StdEncoding.DecodeString(buf.String())
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(md5.New, []byte(a.Password))
+	mac.Write(challenge)
+	resp := fmt.Sprintf("%s %x", a.User, mac.Sum(nil))
+
+	_, _, err = c.ChallengeCmd(base64.StdEncoding.EncodeToString([]byte(resp)))
+	return err
+}
+
+// PlainAuthenticator 实现 SASL PLAIN 登录（RFC 4616）。
+type PlainAuthenticator struct {
+	User     string
+	Password string
+}
+
+func (a PlainAuthenticator) Authenticate(c *Conn) error {
+	payload := base64.StdEncoding.EncodeToString([]byte("\x00" + a.User + "\x00" + a.Password))
+
+	_, isChallenge, err := c.ChallengeCmd("AUTH PLAIN " + payload)
+	if err != nil {
+		return err
+	}
+	if isChallenge {
+		// 服务器不支持初始响应，要求单独发送一行。
+		_, _, err = c.ChallengeCmd(payload)
+	}
+	return err
+}
+
+// XOAuth2Authenticator 实现 Gmail/Outlook 使用的 SASL XOAUTH2 登录。
+type XOAuth2Authenticator struct {
+	User  string
+	Token string
+}
+
+func (a XOAuth2Authenticator) Authenticate(c *Conn) error {
+	payload := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", a.User, a.Token)
+	encoded := base64.StdEncoding.EncodeToString([]byte(payload))
+
+	buf, isChallenge, err := c.ChallengeCmd("AUTH XOAUTH2 " + encoded)
+	if err != nil {
+		return err
+	}
+	if !isChallenge {
+		return nil
+	}
+
+	// 失败时服务器返回一个 base64 编码的错误详情质询，
+	// 客户端需回复空行以让服务器给出最终的 -ERR。
+	_, _, err = c.ChallengeCmd("")
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("XOAUTH2 authentication failed: %s", buf.String())
+}
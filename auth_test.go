@@ -0,0 +1,113 @@
+package gopop3
+
+import (
+	"bufio"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCRAMMD5Authenticator(t *testing.T) {
+	const (
+		user       = "tim"
+		password   = "tanstaaftanstaaf"
+		challenge  = "<1896.697170952@dbc.mtview.ca.us>"
+		wantDigest = "862c05265e165da1b49d2f527ba596de" // hex(HMAC-MD5(challenge, password))
+	)
+
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		line, err := r.ReadString('\n')
+		if err != nil || !strings.HasPrefix(line, "AUTH CRAM-MD5") {
+			t.Errorf("server: unexpected command %q (err=%v)", line, err)
+			return
+		}
+		w.WriteString("+ " + base64.StdEncoding.EncodeToString([]byte(challenge)) + "\r\n")
+		w.Flush()
+
+		resp, err := r.ReadString('\n')
+		if err != nil {
+			t.Errorf("server: reading response: %v", err)
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimRight(resp, "\r\n"))
+		if err != nil {
+			t.Errorf("server: decoding response: %v", err)
+			return
+		}
+
+		if string(decoded) != user+" "+wantDigest {
+			w.WriteString("-ERR digest mismatch\r\n")
+		} else {
+			w.WriteString("+OK\r\n")
+		}
+		w.Flush()
+	})
+
+	if err := c.AuthWith(CRAMMD5Authenticator{User: user, Password: password}); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+}
+
+func TestPlainAuthenticator(t *testing.T) {
+	const user, password = "user", "pass"
+
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		line, err := r.ReadString('\n')
+		if err != nil || !strings.HasPrefix(line, "AUTH PLAIN ") {
+			t.Errorf("server: unexpected command %q (err=%v)", line, err)
+			return
+		}
+
+		payload := strings.TrimRight(strings.TrimPrefix(line, "AUTH PLAIN "), "\r\n")
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			t.Errorf("server: decoding initial response: %v", err)
+			return
+		}
+
+		if string(decoded) != "\x00"+user+"\x00"+password {
+			w.WriteString("-ERR\r\n")
+		} else {
+			w.WriteString("+OK\r\n")
+		}
+		w.Flush()
+	})
+
+	if err := c.AuthWith(PlainAuthenticator{User: user, Password: password}); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+}
+
+func TestXOAuth2AuthenticatorFailure(t *testing.T) {
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		line, err := r.ReadString('\n')
+		if err != nil || !strings.HasPrefix(line, "AUTH XOAUTH2 ") {
+			t.Errorf("server: unexpected command %q (err=%v)", line, err)
+			return
+		}
+
+		errDetail := base64.StdEncoding.EncodeToString([]byte(`{"status":"401"}`))
+		w.WriteString("+ " + errDetail + "\r\n")
+		w.Flush()
+
+		cont, err := r.ReadString('\n')
+		if err != nil {
+			t.Errorf("server: reading continuation: %v", err)
+			return
+		}
+		if got := strings.TrimRight(cont, "\r\n"); got != "" {
+			t.Errorf("server: expected empty continuation line, got %q", got)
+		}
+
+		w.WriteString("-ERR Invalid credentials\r\n")
+		w.Flush()
+	})
+
+	err := c.AuthWith(XOAuth2Authenticator{User: "user@example.com", Token: "bad-token"})
+	if err == nil {
+		t.Fatalf("expected an error for a rejected XOAUTH2 token")
+	}
+	if !strings.Contains(err.Error(), "Invalid credentials") {
+		t.Errorf("error = %v, want it to surface the server's final -ERR", err)
+	}
+}
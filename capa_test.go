@@ -0,0 +1,58 @@
+package gopop3
+
+import (
+	"bufio"
+	"net"
+	"testing"
+)
+
+// newTestConn wires a Conn's reader/writer to one end of an in-memory net.Pipe
+// and runs serverScript against the other end to play the server side of the
+// exchange, so Conn methods can be tested without a real network.
+func newTestConn(t *testing.T, serverScript func(r *bufio.Reader, w *bufio.Writer)) *Conn {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		serverScript(bufio.NewReader(serverConn), bufio.NewWriter(serverConn))
+	}()
+
+	t.Cleanup(func() { clientConn.Close() })
+
+	return &Conn{
+		conn: clientConn,
+		r:    bufio.NewReader(clientConn),
+		w:    bufio.NewWriter(clientConn),
+	}
+}
+
+func TestConnCAPA(t *testing.T) {
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading CAPA command: %v", err)
+			return
+		}
+		w.WriteString("+OK Capability list follows\r\n")
+		w.WriteString("SASL PLAIN CRAM-MD5\r\n")
+		w.WriteString("TOP\r\n")
+		w.WriteString("UIDL\r\n")
+		w.WriteString(".\r\n")
+		w.Flush()
+	})
+
+	caps, err := c.CAPA()
+	if err != nil {
+		t.Fatalf("CAPA() error = %v", err)
+	}
+
+	if sasl := caps["SASL"]; len(sasl) != 2 || sasl[0] != "PLAIN" || sasl[1] != "CRAM-MD5" {
+		t.Errorf("caps[SASL] = %v, want [PLAIN CRAM-MD5]", sasl)
+	}
+	if _, ok := caps["TOP"]; !ok {
+		t.Errorf("caps missing TOP")
+	}
+	if _, ok := caps["UIDL"]; !ok {
+		t.Errorf("caps missing UIDL")
+	}
+}
@@ -0,0 +1,148 @@
+package gopop3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/emersion/go-message"
+)
+
+// NewConnContext 和 NewConn 类似，但使用 net.Dialer.DialContext 拨号，
+// 因此调用方可以通过 ctx 提前取消连接建立过程。
+func (c *Client) NewConnContext(ctx context.Context) (*Conn, error) {
+	if c.opt.STLS && c.opt.TLSEnabled {
+		return nil, errors.New("gopop3: Option.STLS and Option.TLSEnabled are mutually exclusive")
+	}
+
+	addr := fmt.Sprintf("%s:%d", c.opt.Host, c.opt.Port)
+
+	d := net.Dialer{Timeout: c.opt.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.opt.TLSEnabled {
+		tlsCfg := tls.Config{}
+		if c.opt.TLSSkipVerify {
+			tlsCfg.InsecureSkipVerify = c.opt.TLSSkipVerify
+		} else {
+			tlsCfg.ServerName = c.opt.Host
+		}
+
+		conn = tls.Client(conn, &tlsCfg)
+	}
+
+	pCon := &Conn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+		opt:  c.opt,
+	}
+
+	greeting, err := pCon.ReadOneContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pCon.apopTimestamp = apopTimestampRe.FindString(string(greeting))
+
+	if c.opt.STLS {
+		if err := pCon.StartTLS(&tls.Config{
+			ServerName:         c.opt.Host,
+			InsecureSkipVerify: c.opt.TLSSkipVerify,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return pCon, nil
+}
+
+// withDeadline 在 ctx 有截止时间时将其应用到 conn 上，并启动一个 watcher，
+// 在 ctx 被取消（含超时）时关闭 conn 以中断任何正在阻塞的读写。
+// 返回的 cleanup 函数必须在调用方的命令完成后调用，以停止 watcher 并清除连接的截止时间。
+func (c *Conn) withDeadline(ctx context.Context) (cleanup func(), err error) {
+	hasDeadline := false
+	if dl, ok := ctx.Deadline(); ok {
+		if err := c.conn.SetDeadline(dl); err != nil {
+			return func() {}, err
+		}
+		hasDeadline = true
+		// 阻止 Send/ReadOne/ReadAll 用 Option.ReadTimeout/WriteTimeout 覆盖刚设置的 ctx 截止时间。
+		c.ctxDeadlineActive = true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.conn.Close()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		if hasDeadline {
+			c.ctxDeadlineActive = false
+			c.conn.SetDeadline(time.Time{})
+		}
+	}, nil
+}
+
+// ReadOneContext 和 ReadOne 类似，但受 ctx 取消/超时约束。
+func (c *Conn) ReadOneContext(ctx context.Context) ([]byte, error) {
+	cleanup, err := c.withDeadline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return c.ReadOne()
+}
+
+// CmdContext 和 Cmd 类似，但受 ctx 取消/超时约束：ctx 的截止时间（若有）会被设为
+// 底层连接的读写截止时间，同时启动一个 watcher，在 ctx 被取消时关闭连接。
+func (c *Conn) CmdContext(ctx context.Context, cmd string, isMulti bool, args ...interface{}) (*bytes.Buffer, error) {
+	cleanup, err := c.withDeadline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return c.Cmd(cmd, isMulti, args...)
+}
+
+// ListContext 和 List 类似，但受 ctx 取消/超时约束。
+func (c *Conn) ListContext(ctx context.Context, msgID int) ([]MessageID, error) {
+	var (
+		buf *bytes.Buffer
+		err error
+	)
+
+	if msgID <= 0 {
+		buf, err = c.CmdContext(ctx, "LIST", true)
+	} else {
+		buf, err = c.CmdContext(ctx, "LIST", false, msgID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseListResp(buf)
+}
+
+// RetrContext 和 Retr 类似，但受 ctx 取消/超时约束。
+func (c *Conn) RetrContext(ctx context.Context, msgID int) (*message.Entity, error) {
+	b, err := c.CmdContext(ctx, "RETR", true, msgID)
+	if err != nil {
+		return nil, err
+	}
+	return parseRetrResp(b)
+}
@@ -0,0 +1,73 @@
+package gopop3
+
+import (
+	"bufio"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConnCmdContextTimeout(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+		// Simulate a server that hangs instead of responding.
+		<-block
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.CmdContext(ctx, "NOOP", false)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("CmdContext returned nil error, want a timeout/closed-connection error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("CmdContext did not return after its context's deadline elapsed")
+	}
+}
+
+func TestConnCmdContextCancel(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+		<-block
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.CmdContext(ctx, "NOOP", false)
+		errCh <- err
+	}()
+
+	// Give the command time to be sent before cancelling, so the watcher
+	// observes cancellation mid-read rather than before the write.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("CmdContext returned nil error, want an error after ctx cancellation")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("CmdContext did not return after ctx was cancelled")
+	}
+}
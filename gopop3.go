@@ -8,8 +8,6 @@ import (
 	"fmt"
 	"github.com/emersion/go-message"
 	_ "github.com/emersion/go-message/charset"
-	"io"
-	"log"
 	"net"
 	"strconv"
 	"strings"
@@ -26,6 +24,18 @@ type Conn struct {
 	conn net.Conn
 	r    *bufio.Reader
 	w    *bufio.Writer
+
+	// opt 保存创建该连接时所用的 Client 配置，用于派生每条命令的默认读写超时。
+	opt Option
+
+	// ctxDeadlineActive 为 true 时，表示某个 *Context 方法已经根据 ctx 的截止时间
+	// 设置了连接的读写截止时间，此时 Send/ReadOne/ReadAll 不应再用 Option 的超时
+	// 覆盖它（ctx 的截止时间优先于 Option.ReadTimeout/WriteTimeout）。
+	ctxDeadlineActive bool
+
+	// apopTimestamp 是问候语中携带的 APOP 时间戳（含尖括号），
+	// 服务器不支持 APOP 时为空字符串。
+	apopTimestamp string
 }
 
 // Option 代表客户端配置。
@@ -38,6 +48,20 @@ type Option struct {
 
 	TLSEnabled    bool `json:"tls_enabled"`
 	TLSSkipVerify bool `json:"tls_skip_verify"`
+
+	// STLS 为 true 时，NewConn 会在明文端口连接成功后自动发出 STLS 命令升级为 TLS。
+	// 与 TLSEnabled（直接以 TLS 方式拨号）互斥，通常用于 110 端口；两者同时为 true 时 NewConn 会返回错误。
+	STLS bool `json:"stls"`
+
+	// StrictAuth 为 true 时，Auth 会先查询 CAPA，如果服务器仅声明了 SASL 而未声明 USER，
+	// 就直接返回错误，不再尝试明文 USER/PASS。默认为 false：USER 在 RFC 2449 中是可选声明，
+	// 许多支持 USER/PASS 的服务器并不会在 CAPA 里列出它，默认开启会误伤这些服务器。
+	StrictAuth bool `json:"strict_auth"`
+
+	// ReadTimeout/WriteTimeout 为每条命令的读/写设置默认超时，零值表示不设置超时。
+	// Conn 的 *Context 方法会用 ctx 的截止时间覆盖它们。
+	ReadTimeout  time.Duration `json:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout"`
 }
 
 // MessageID 包含单个消息的 ID 和大小。
@@ -52,6 +76,12 @@ type MessageID struct {
 type MailInfo struct {
 	// 邮件来自
 	From string `json:"from"`
+	// 收件人
+	To []string `json:"to,omitempty"`
+	// 抄送
+	Cc []string `json:"cc,omitempty"`
+	// 密送
+	Bcc []string `json:"bcc,omitempty"`
 	// 收件时间
 	Time int64 `json:"time"`
 	// 邮件标题
@@ -60,6 +90,23 @@ type MailInfo struct {
 	Content string `json:"content"`
 	// 邮件HTML格式内容
 	HtmlContent string `json:"html_content"`
+	// Message-Id 头
+	MessageID string `json:"message_id,omitempty"`
+	// In-Reply-To 头，标识本邮件回复的消息
+	InReplyTo string `json:"in_reply_to,omitempty"`
+	// References 头，包含会话中前序消息的 Message-Id 列表
+	References []string `json:"references,omitempty"`
+	// 附件列表
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment 是邮件中的一个附件或内联资源。
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+	Disposition string `json:"disposition"`
+	Data        []byte `json:"data"`
 }
 
 var (
@@ -82,6 +129,10 @@ func NewPop3Client(opt Option) *Client {
 
 // NewConn 创建并返回实时 POP3 服务器连接。
 func (c *Client) NewConn() (*Conn, error) {
+	if c.opt.STLS && c.opt.TLSEnabled {
+		return nil, errors.New("gopop3: Option.STLS and Option.TLSEnabled are mutually exclusive")
+	}
+
 	var (
 		addr = fmt.Sprintf("%s:%d", c.opt.Host, c.opt.Port)
 	)
@@ -107,17 +158,53 @@ func (c *Client) NewConn() (*Conn, error) {
 		conn: conn,
 		r:    bufio.NewReader(conn),
 		w:    bufio.NewWriter(conn),
+		opt:  c.opt,
 	}
 
 	// 通过问候语来验证连接。
-	if _, err := pCon.ReadOne(); err != nil {
+	greeting, err := pCon.ReadOne()
+	if err != nil {
 		return nil, err
 	}
+	pCon.apopTimestamp = apopTimestampRe.FindString(string(greeting))
+
+	if c.opt.STLS {
+		if err := pCon.StartTLS(&tls.Config{
+			ServerName:         c.opt.Host,
+			InsecureSkipVerify: c.opt.TLSSkipVerify,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	return pCon, nil
 }
 
+// applyWriteDeadline 在设置了 Option.WriteTimeout 时为底层连接设置一个写截止时间。
+// 如果调用方正通过某个 *Context 方法设置了基于 ctx 的截止时间，则不会覆盖它。
+func (c *Conn) applyWriteDeadline() error {
+	if c.opt.WriteTimeout > 0 && !c.ctxDeadlineActive {
+		return c.conn.SetWriteDeadline(time.Now().Add(c.opt.WriteTimeout))
+	}
+	return nil
+}
+
+// applyReadDeadline 在设置了 Option.ReadTimeout 时为底层连接设置一个读截止时间。
+// 如果调用方正通过某个 *Context 方法设置了基于 ctx 的截止时间，则不会覆盖它。
+func (c *Conn) applyReadDeadline() error {
+	if c.opt.ReadTimeout > 0 && !c.ctxDeadlineActive {
+		return c.conn.SetReadDeadline(time.Now().Add(c.opt.ReadTimeout))
+	}
+	return nil
+}
+
 // Send 向服务器发送一个 POP3 命令。给定的命令后缀为“\r\n”。
+// 如果设置了 Option.WriteTimeout，写入会在该时长后超时；
+// 但如果调用方正通过某个 *Context 方法设置了基于 ctx 的截止时间，则以后者为准。
 func (c *Conn) Send(b string) error {
+	if err := c.applyWriteDeadline(); err != nil {
+		return err
+	}
 	if _, err := c.w.WriteString(b + "\r\n"); err != nil {
 		return err
 	}
@@ -157,7 +244,13 @@ func (c *Conn) Cmd(cmd string, isMulti bool, args ...interface{}) (*bytes.Buffer
 }
 
 // ReadOne 从 conn 读取单行响应。
+// 如果设置了 Option.ReadTimeout，读取会在该时长后超时；
+// 但如果调用方正通过某个 *Context 方法设置了基于 ctx 的截止时间，则以后者为准。
 func (c *Conn) ReadOne() ([]byte, error) {
+	if err := c.applyReadDeadline(); err != nil {
+		return nil, err
+	}
+
 	b, _, err := c.r.ReadLine()
 	if err != nil {
 		return nil, err
@@ -168,7 +261,13 @@ func (c *Conn) ReadOne() ([]byte, error) {
 }
 
 // ReadAll 从连接中读取所有行，直到 POP3 多行终止符“.”遇到并返回所有读取行的 bytes.Buffer。
+// 如果设置了 Option.ReadTimeout，整个多行响应共用一个读取截止时间；
+// 但如果调用方正通过某个 *Context 方法设置了基于 ctx 的截止时间，则以后者为准。
 func (c *Conn) ReadAll() (*bytes.Buffer, error) {
+	if err := c.applyReadDeadline(); err != nil {
+		return nil, err
+	}
+
 	buf := &bytes.Buffer{}
 
 	for {
@@ -194,7 +293,19 @@ func (c *Conn) ReadAll() (*bytes.Buffer, error) {
 }
 
 // Auth 通过服务器验证给定的凭据。
+// 如果启用了 Option.StrictAuth 且服务器的 CAPA 中包含 SASL 但没有 USER，
+// 说明服务器只接受 SASL 机制，这里会直接返回错误而不是继续尝试明文 USER/PASS。
 func (c *Conn) Auth(user, password string) error {
+	if c.opt.StrictAuth {
+		if caps, err := c.CAPA(); err == nil {
+			if _, hasSASL := caps["SASL"]; hasSASL {
+				if _, hasUser := caps["USER"]; !hasUser {
+					return errors.New("server only advertises SASL authentication; use AuthWith instead of Auth")
+				}
+			}
+		}
+	}
+
 	if err := c.User(user); err != nil {
 		return err
 	}
@@ -267,6 +378,11 @@ func (c *Conn) List(msgID int) ([]MessageID, error) {
 		return nil, err
 	}
 
+	return parseListResp(buf)
+}
+
+// parseListResp 解析 LIST 响应中的 "id size" 行。
+func parseListResp(buf *bytes.Buffer) ([]MessageID, error) {
 	var (
 		out   []MessageID
 		lines = bytes.Split(buf.Bytes(), lineBreak)
@@ -301,6 +417,11 @@ func (c *Conn) Retr(msgID int) (*message.Entity, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseRetrResp(b)
+}
+
+// parseRetrResp 将 RETR 响应体解析为 message.Entity，未知字符集不视为致命错误。
+func parseRetrResp(b *bytes.Buffer) (*message.Entity, error) {
 	m, err := message.Read(b)
 	if err != nil {
 		if !message.IsUnknownCharset(err) {
@@ -364,6 +485,51 @@ func (c *Conn) Quit() error {
 	return c.conn.Close()
 }
 
+// CAPA 向服务器发出 CAPA 命令并解析多行能力列表。
+// 形如 `SASL PLAIN CRAM-MD5` 的行会被拆分为键 "SASL" 到值 ["PLAIN", "CRAM-MD5"]，
+// 而像 `UIDL`、`PIPELINING`、`STLS` 这样没有附加参数的能力，其值为空切片。
+func (c *Conn) CAPA() (map[string][]string, error) {
+	buf, err := c.Cmd("CAPA", true)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := make(map[string][]string)
+	for _, l := range bytes.Split(buf.Bytes(), lineBreak) {
+		f := bytes.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+
+		name := strings.ToUpper(string(f[0]))
+		var args []string
+		for _, a := range f[1:] {
+			args = append(args, string(a))
+		}
+		caps[name] = args
+	}
+
+	return caps, nil
+}
+
+// StartTLS 发出 STLS 命令，并将底层 net.Conn 包装为 tls.Client，
+// 同时重新初始化 bufio.Reader/Writer，以便在明文端口（如 110）上协商 TLS。
+func (c *Conn) StartTLS(cfg *tls.Config) error {
+	if _, err := c.Cmd("STLS", false); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.conn, cfg)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	c.w = bufio.NewWriter(tlsConn)
+	return nil
+}
+
 // parseResp 检查响应是否是以 `-ERR` 开头的错误，并返回错误指示符成功的消息。
 // 对于成功的 `+OK` 消息，它返回剩余的响应字节。
 func parseResp(b []byte) ([]byte, error) {
@@ -382,44 +548,3 @@ func parseResp(b []byte) ([]byte, error) {
 		return nil, fmt.Errorf("unknown response: %s. Neither -ERR, nor +OK", string(b))
 	}
 }
-
-// 针对163邮箱，其他邮箱没有验证解析格式
-func ParseMail(m *message.Entity) (*MailInfo, error) {
-	received, err := m.Header.Text("Received")
-	if err != nil {
-		return nil, err
-	}
-	receiveds := strings.Split(received, ";")
-	froms := strings.Split(receiveds[0], " ")
-	date := strings.ReplaceAll(receiveds[1], "(CST)", "")
-	tp, err := time.Parse(" Mon, 2 Jan 2006 15:04:05 -0700 ", date)
-	if err != nil {
-		return nil, err
-	}
-	text, _ := m.Header.Text("Subject")
-	mailInfo := &MailInfo{
-		From:  strings.ReplaceAll(froms[1], "$", "@"),
-		Title: text,
-		Time:  tp.Unix(),
-	}
-	if mr := m.MultipartReader(); mr != nil {
-		for {
-			p, err := mr.NextPart()
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				log.Fatal(err)
-			}
-			t, _, _ := p.Header.ContentType()
-
-			b, err := io.ReadAll(p.Body)
-			if t == "text/plain" {
-				mailInfo.Content = string(b)
-			}
-			if t == "text/html" {
-				mailInfo.HtmlContent = string(b)
-			}
-		}
-	}
-	return mailInfo, nil
-}
@@ -0,0 +1,193 @@
+package gopop3
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+)
+
+// dateCommentRe 匹配日期字符串末尾的注释，例如 "Mon, 2 Jan 2006 15:04:05 -0700 (CST)" 中的 "(CST)"。
+var dateCommentRe = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+
+// fallbackDateLayouts 是 mail.ParseDate 无法识别时尝试的常见非标准日期格式。
+var fallbackDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+}
+
+// parseDate 按 RFC 5322 解析日期头，失败时回退到一组常见的非标准格式。
+func parseDate(s string) (time.Time, error) {
+	if t, err := mail.ParseDate(s); err == nil {
+		return t, nil
+	}
+
+	cleaned := strings.TrimSpace(dateCommentRe.ReplaceAllString(s, ""))
+	for _, layout := range fallbackDateLayouts {
+		if t, err := time.Parse(layout, cleaned); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to parse date %q", s)
+}
+
+// formatAddressList 将 net/mail 解析出的地址列表格式化为 "Name <addr>" 形式的字符串切片。
+// 地址没有显示名时仅返回 email 地址。
+func formatAddressList(addrs []*mail.Address) []string {
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if a.Name != "" {
+			out = append(out, fmt.Sprintf("%s <%s>", a.Name, a.Address))
+		} else {
+			out = append(out, a.Address)
+		}
+	}
+	return out
+}
+
+// headerAddressList 读取并解析 m 中名为 key 的地址头（From/To/Cc/Bcc）。
+// 头不存在时返回空切片，而不是错误。
+func headerAddressList(m *message.Entity, key string) ([]string, error) {
+	v, err := m.Header.Text(key)
+	if err != nil || v == "" {
+		return nil, nil
+	}
+
+	addrs, err := mail.ParseAddressList(v)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s header: %w", key, err)
+	}
+	return formatAddressList(addrs), nil
+}
+
+// ParseMail 将一封已下载的消息解析为 MailInfo，适用于任意遵循 RFC 5322/MIME 的发件方，
+// 而不仅限于某一家邮箱服务商。地址头通过 net/mail 解析，日期优先按 RFC 5322 解析，
+// 无法识别时回退到几种常见的非标准格式。multipart 内容会被递归展开，
+// 无法归入正文的部分按 Content-Disposition 收集为附件。
+func ParseMail(m *message.Entity) (*MailInfo, error) {
+	from, err := headerAddressList(m, "From")
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := headerAddressList(m, "To")
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := headerAddressList(m, "Cc")
+	if err != nil {
+		return nil, err
+	}
+
+	bcc, err := headerAddressList(m, "Bcc")
+	if err != nil {
+		return nil, err
+	}
+
+	var mailTime int64
+	if date, err := m.Header.Text("Date"); err == nil && date != "" {
+		tp, err := parseDate(date)
+		if err != nil {
+			return nil, err
+		}
+		mailTime = tp.Unix()
+	}
+
+	title, _ := m.Header.Text("Subject")
+
+	var references []string
+	if refs, err := m.Header.Text("References"); err == nil && refs != "" {
+		references = strings.Fields(refs)
+	}
+
+	mailInfo := &MailInfo{
+		Time:       mailTime,
+		Title:      title,
+		To:         to,
+		Cc:         cc,
+		Bcc:        bcc,
+		MessageID:  strings.TrimSpace(m.Header.Get("Message-Id")),
+		InReplyTo:  strings.TrimSpace(m.Header.Get("In-Reply-To")),
+		References: references,
+	}
+	if len(from) > 0 {
+		mailInfo.From = from[0]
+	}
+
+	if err := walkMailParts(m, mailInfo); err != nil {
+		return nil, err
+	}
+
+	return mailInfo, nil
+}
+
+// walkMailParts 递归遍历 multipart/* 的各层分片，将正文归入 Content/HtmlContent，
+// 其余的（包括内联资源）收集为 Attachments。
+func walkMailParts(e *message.Entity, mailInfo *MailInfo) error {
+	mr := e.MultipartReader()
+	if mr == nil {
+		return collectMailPart(e, mailInfo)
+	}
+
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := walkMailParts(p, mailInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func collectMailPart(p *message.Entity, mailInfo *MailInfo) error {
+	contentType, ctParams, err := p.Header.ContentType()
+	if err != nil {
+		contentType = "text/plain"
+	}
+
+	disposition, dispParams, _ := p.Header.ContentDisposition()
+
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = ctParams["name"]
+	}
+
+	b, err := io.ReadAll(p.Body)
+	if err != nil {
+		return err
+	}
+
+	isAttachment := disposition == "attachment"
+	switch {
+	case contentType == "text/plain" && !isAttachment:
+		mailInfo.Content = string(b)
+	case contentType == "text/html" && !isAttachment:
+		mailInfo.HtmlContent = string(b)
+	default:
+		// Content-Disposition 为 attachment 的分片，以及正文两种内容类型以外的分片，一律视为附件
+		// （含没有 Content-Disposition/filename 的内联资源），否则这部分内容会在 Content/HtmlContent 之外被悄悄丢弃。
+		mailInfo.Attachments = append(mailInfo.Attachments, &Attachment{
+			Filename:    filename,
+			ContentType: contentType,
+			ContentID:   strings.Trim(p.Header.Get("Content-Id"), "<>"),
+			Disposition: disposition,
+			Data:        b,
+		})
+	}
+	return nil
+}
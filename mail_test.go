@@ -0,0 +1,139 @@
+package gopop3
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-message"
+)
+
+func TestParseDateFallback(t *testing.T) {
+	raw := "Mon, 2 Jan 2006 15:04:05 -0700 (CST)"
+
+	got, err := parseDate(raw)
+	if err != nil {
+		t.Fatalf("parseDate(%q) error = %v", raw, err)
+	}
+
+	want, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 -0700")
+	if err != nil {
+		t.Fatalf("time.Parse reference: %v", err)
+	}
+
+	if !got.Equal(want) {
+		t.Errorf("parseDate(%q) = %v, want %v", raw, got, want)
+	}
+}
+
+func TestParseMail(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: Alice <alice@example.com>",
+		"To: Bob <bob@example.com>",
+		"Cc: Carol <carol@example.com>",
+		"Bcc: Dave <dave@example.com>",
+		"Subject: Hello",
+		"Date: Mon, 2 Jan 2006 15:04:05 -0700",
+		"Message-Id: <abc@example.com>",
+		"Content-Type: multipart/mixed; boundary=outer",
+		"",
+		"--outer",
+		"Content-Type: multipart/alternative; boundary=inner",
+		"",
+		"--inner",
+		"Content-Type: text/plain",
+		"",
+		"plain body",
+		"--inner",
+		"Content-Type: text/html",
+		"",
+		"<p>html body</p>",
+		"--inner--",
+		"--outer",
+		"Content-Type: application/octet-stream",
+		"Content-Disposition: attachment; filename=\"a.txt\"",
+		"",
+		"attachment-data",
+		"--outer",
+		"Content-Type: image/png",
+		"Content-Disposition: inline",
+		"Content-Id: <logo>",
+		"",
+		"png-bytes",
+		"--outer--",
+		"",
+	}, "\r\n")
+
+	m, err := message.Read(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("message.Read: %v", err)
+	}
+
+	mailInfo, err := ParseMail(m)
+	if err != nil {
+		t.Fatalf("ParseMail: %v", err)
+	}
+
+	if mailInfo.From != "Alice <alice@example.com>" {
+		t.Errorf("From = %q", mailInfo.From)
+	}
+	if want := []string{"Bob <bob@example.com>"}; !equalStrings(mailInfo.To, want) {
+		t.Errorf("To = %v, want %v", mailInfo.To, want)
+	}
+	if want := []string{"Carol <carol@example.com>"}; !equalStrings(mailInfo.Cc, want) {
+		t.Errorf("Cc = %v, want %v", mailInfo.Cc, want)
+	}
+	if want := []string{"Dave <dave@example.com>"}; !equalStrings(mailInfo.Bcc, want) {
+		t.Errorf("Bcc = %v, want %v", mailInfo.Bcc, want)
+	}
+	if mailInfo.MessageID != "<abc@example.com>" {
+		t.Errorf("MessageID = %q", mailInfo.MessageID)
+	}
+	if mailInfo.Content != "plain body" {
+		t.Errorf("Content = %q", mailInfo.Content)
+	}
+	if mailInfo.HtmlContent != "<p>html body</p>" {
+		t.Errorf("HtmlContent = %q", mailInfo.HtmlContent)
+	}
+	if len(mailInfo.Attachments) != 2 {
+		t.Fatalf("len(Attachments) = %d, want 2", len(mailInfo.Attachments))
+	}
+
+	att := mailInfo.Attachments[0]
+	if att.Filename != "a.txt" {
+		t.Errorf("Attachments[0].Filename = %q", att.Filename)
+	}
+	if string(att.Data) != "attachment-data" {
+		t.Errorf("Attachments[0].Data = %q", att.Data)
+	}
+	if att.Disposition != "attachment" {
+		t.Errorf("Attachments[0].Disposition = %q", att.Disposition)
+	}
+
+	// 没有 filename、仅带 Content-Id 的内联资源也必须被当作附件收集，而不是被丢弃。
+	inline := mailInfo.Attachments[1]
+	if inline.Filename != "" {
+		t.Errorf("Attachments[1].Filename = %q, want empty", inline.Filename)
+	}
+	if inline.ContentID != "logo" {
+		t.Errorf("Attachments[1].ContentID = %q, want %q", inline.ContentID, "logo")
+	}
+	if string(inline.Data) != "png-bytes" {
+		t.Errorf("Attachments[1].Data = %q", inline.Data)
+	}
+	if inline.Disposition != "inline" {
+		t.Errorf("Attachments[1].Disposition = %q", inline.Disposition)
+	}
+}
+
+func equalStrings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
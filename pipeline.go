@@ -0,0 +1,139 @@
+package gopop3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-message"
+)
+
+// Pipeline 一次性写出 cmds 中的所有命令（单次 Flush），随后按顺序读取每条命令的响应。
+// multiLine[i] 为 true 时，第 i 条命令的响应按多行响应读取（ReadAll），否则按单行读取（ReadOne）。
+// 返回的 bufs/errs 与 cmds 一一对应；某条命令出错不会中断后续响应的读取。
+// 仅应对服务器在 CAPA 中声明了 PIPELINING 的连接使用。
+// 如果设置了 Option.WriteTimeout/ReadTimeout，写入和每条响应的读取都会分别应用对应的超时；
+// 要改为遵循某个 context.Context，使用 PipelineContext。
+func (c *Conn) Pipeline(cmds []string, multiLine []bool) (bufs []*bytes.Buffer, errs []error) {
+	bufs = make([]*bytes.Buffer, len(cmds))
+	errs = make([]error, len(cmds))
+
+	if err := c.applyWriteDeadline(); err != nil {
+		return bufs, fillErr(errs, err)
+	}
+
+	for _, cmd := range cmds {
+		if _, err := c.w.WriteString(cmd + "\r\n"); err != nil {
+			return bufs, fillErr(errs, err)
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return bufs, fillErr(errs, err)
+	}
+
+	for i, multi := range multiLine {
+		b, err := c.ReadOne()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if multi {
+			buf, err := c.ReadAll()
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			bufs[i] = buf
+		} else {
+			bufs[i] = bytes.NewBuffer(b)
+		}
+	}
+
+	return bufs, errs
+}
+
+// PipelineContext 和 Pipeline 类似，但受 ctx 取消/超时约束：ctx 的截止时间（若有）
+// 会覆盖 Option.WriteTimeout/ReadTimeout 成为整条流水线共用的读写截止时间，
+// ctx 被取消时会关闭底层连接以中断正在阻塞的读写。
+func (c *Conn) PipelineContext(ctx context.Context, cmds []string, multiLine []bool) ([]*bytes.Buffer, []error) {
+	cleanup, err := c.withDeadline(ctx)
+	if err != nil {
+		return make([]*bytes.Buffer, len(cmds)), fillErr(make([]error, len(cmds)), err)
+	}
+	defer cleanup()
+
+	return c.Pipeline(cmds, multiLine)
+}
+
+// fillErr 将 err 填入 errs 的每一项，用于写入阶段失败、后续响应均无法读取的情况。
+func fillErr(errs []error, err error) []error {
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// RetrBatch 批量下载 ids 指定的消息。当服务器在 CAPA 中声明了 PIPELINING 时，
+// 通过 Pipeline 一次性发出所有 RETR 命令以节省往返延迟；否则回退为逐条串行 RETR。
+// 返回的两个 map 均以消息 ID 为键，分别是成功解析的消息和对应的错误。
+func (c *Conn) RetrBatch(ids []int) (map[int]*message.Entity, map[int]error) {
+	out := make(map[int]*message.Entity, len(ids))
+	errOut := make(map[int]error)
+
+	pipelined := false
+	if caps, err := c.CAPA(); err == nil {
+		_, pipelined = caps["PIPELINING"]
+	}
+
+	if !pipelined {
+		for _, id := range ids {
+			m, err := c.Retr(id)
+			if err != nil {
+				errOut[id] = err
+				continue
+			}
+			out[id] = m
+		}
+		return out, errOut
+	}
+
+	cmds := make([]string, len(ids))
+	multi := make([]bool, len(ids))
+	for i, id := range ids {
+		cmds[i] = fmt.Sprintf("RETR %d", id)
+		multi[i] = true
+	}
+
+	bufs, errs := c.Pipeline(cmds, multi)
+	for i, id := range ids {
+		if errs[i] != nil {
+			errOut[id] = errs[i]
+			continue
+		}
+
+		m, err := message.Read(bufs[i])
+		if err != nil && !message.IsUnknownCharset(err) {
+			errOut[id] = err
+			continue
+		}
+		out[id] = m
+	}
+
+	return out, errOut
+}
+
+// RetrBatchContext 和 RetrBatch 类似，但受 ctx 取消/超时约束，参见 PipelineContext。
+func (c *Conn) RetrBatchContext(ctx context.Context, ids []int) (map[int]*message.Entity, map[int]error) {
+	cleanup, err := c.withDeadline(ctx)
+	if err != nil {
+		errOut := make(map[int]error, len(ids))
+		for _, id := range ids {
+			errOut[id] = err
+		}
+		return make(map[int]*message.Entity), errOut
+	}
+	defer cleanup()
+
+	return c.RetrBatch(ids)
+}
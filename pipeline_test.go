@@ -0,0 +1,55 @@
+package gopop3
+
+import (
+	"bufio"
+	"testing"
+)
+
+func TestConnPipeline(t *testing.T) {
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		// RETR 1: multi-line success.
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading cmd 1: %v", err)
+			return
+		}
+		w.WriteString("+OK 1 octets\r\n")
+		w.WriteString("line one\r\n")
+		w.WriteString(".\r\n")
+
+		// NOOP: single-line success.
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading cmd 2: %v", err)
+			return
+		}
+		w.WriteString("+OK\r\n")
+
+		// RETR 99: single-line error, reported for this command only.
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading cmd 3: %v", err)
+			return
+		}
+		w.WriteString("-ERR no such message\r\n")
+
+		w.Flush()
+	})
+
+	bufs, errs := c.Pipeline(
+		[]string{"RETR 1", "NOOP", "RETR 99"},
+		[]bool{true, false, false},
+	)
+
+	if errs[0] != nil {
+		t.Fatalf("cmd 0: unexpected error %v", errs[0])
+	}
+	if got := bufs[0].String(); got != "line one\r\n" {
+		t.Errorf("cmd 0 buf = %q, want %q", got, "line one\r\n")
+	}
+
+	if errs[1] != nil {
+		t.Fatalf("cmd 1: unexpected error %v", errs[1])
+	}
+
+	if errs[2] == nil {
+		t.Fatalf("cmd 2: expected error, got nil")
+	}
+}
@@ -0,0 +1,149 @@
+package gopop3
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/emersion/go-message"
+)
+
+// Uidl 向服务器发出 UIDL 命令，返回（消息 ID，UID）对的列表。
+// 如果可选的 msgID > 0，则仅查询该特定消息（单行响应）；否则返回全部消息（多行响应）。
+func (c *Conn) Uidl(msgID int) ([]MessageID, error) {
+	var (
+		buf *bytes.Buffer
+		err error
+	)
+
+	if msgID <= 0 {
+		buf, err = c.Cmd("UIDL", true)
+	} else {
+		buf, err = c.Cmd("UIDL", false, msgID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out   []MessageID
+		lines = bytes.Split(buf.Bytes(), lineBreak)
+	)
+
+	for _, l := range lines {
+		// id uid
+		f := bytes.Fields(l)
+		if len(f) == 0 {
+			break
+		}
+
+		id, err := strconv.Atoi(string(f[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, MessageID{ID: id, UID: string(f[1])})
+	}
+
+	return out, nil
+}
+
+// UIDStore 记录已经拉取过的消息 UID，供 FetchNew 增量拉取使用。
+// POP3 邮箱通常会把消息留在服务器上，增量拉取依赖 UIDL 返回的 UID 在多次会话间保持稳定。
+type UIDStore interface {
+	Has(uid string) bool
+	Add(uid string) error
+}
+
+// FetchNew 列出服务器上所有消息的 UID，跳过 store 中已记录的，
+// 仅 RETR 尚未拉取过的消息，并在拉取成功后将其 UID 写入 store。
+func (c *Conn) FetchNew(store UIDStore) ([]*message.Entity, error) {
+	uids, err := c.Uidl(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*message.Entity
+	for _, u := range uids {
+		if store.Has(u.UID) {
+			continue
+		}
+
+		m, err := c.Retr(u.ID)
+		if err != nil {
+			return out, err
+		}
+
+		if err := store.Add(u.UID); err != nil {
+			return out, err
+		}
+
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+// FileUIDStore 是 UIDStore 的默认实现，将已见过的 UID 持久化为一个 JSON 文件。
+type FileUIDStore struct {
+	path string
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewFileUIDStore 加载（如果存在）path 指向的 JSON 文件并返回一个 FileUIDStore。
+// 文件不存在时视为空存储，首次 Add 时会创建该文件。
+func NewFileUIDStore(path string) (*FileUIDStore, error) {
+	s := &FileUIDStore{
+		path: path,
+		seen: make(map[string]bool),
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var uids []string
+	if err := json.Unmarshal(b, &uids); err != nil {
+		return nil, err
+	}
+	for _, uid := range uids {
+		s.seen[uid] = true
+	}
+
+	return s, nil
+}
+
+func (s *FileUIDStore) Has(uid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[uid]
+}
+
+func (s *FileUIDStore) Add(uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen[uid] {
+		return nil
+	}
+	s.seen[uid] = true
+
+	uids := make([]string, 0, len(s.seen))
+	for u := range s.seen {
+		uids = append(uids, u)
+	}
+
+	b, err := json.Marshal(uids)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, b, 0644)
+}
@@ -0,0 +1,95 @@
+package gopop3
+
+import (
+	"bufio"
+	"testing"
+)
+
+// fakeUIDStore is an in-memory UIDStore for exercising FetchNew without touching disk.
+type fakeUIDStore struct {
+	seen map[string]bool
+}
+
+func newFakeUIDStore(seen ...string) *fakeUIDStore {
+	s := &fakeUIDStore{seen: make(map[string]bool)}
+	for _, uid := range seen {
+		s.seen[uid] = true
+	}
+	return s
+}
+
+func (s *fakeUIDStore) Has(uid string) bool { return s.seen[uid] }
+
+func (s *fakeUIDStore) Add(uid string) error {
+	s.seen[uid] = true
+	return nil
+}
+
+func TestConnUidl(t *testing.T) {
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading UIDL command: %v", err)
+			return
+		}
+		w.WriteString("+OK\r\n")
+		w.WriteString("1 uid-one\r\n")
+		w.WriteString("2 uid-two\r\n")
+		w.WriteString(".\r\n")
+		w.Flush()
+	})
+
+	uids, err := c.Uidl(0)
+	if err != nil {
+		t.Fatalf("Uidl: %v", err)
+	}
+
+	want := []MessageID{{ID: 1, UID: "uid-one"}, {ID: 2, UID: "uid-two"}}
+	if len(uids) != len(want) {
+		t.Fatalf("Uidl() = %v, want %v", uids, want)
+	}
+	for i := range want {
+		if uids[i].ID != want[i].ID || uids[i].UID != want[i].UID {
+			t.Errorf("uids[%d] = %+v, want %+v", i, uids[i], want[i])
+		}
+	}
+}
+
+func TestConnFetchNew(t *testing.T) {
+	c := newTestConn(t, func(r *bufio.Reader, w *bufio.Writer) {
+		// UIDL
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading UIDL command: %v", err)
+			return
+		}
+		w.WriteString("+OK\r\n")
+		w.WriteString("1 uid-one\r\n")
+		w.WriteString("2 uid-two\r\n")
+		w.WriteString(".\r\n")
+		w.Flush()
+
+		// RETR 2: only the unseen message should be fetched.
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Errorf("server: reading RETR command: %v", err)
+			return
+		}
+		w.WriteString("+OK\r\n")
+		w.WriteString("Subject: hi\r\n")
+		w.WriteString("\r\n")
+		w.WriteString("body\r\n")
+		w.WriteString(".\r\n")
+		w.Flush()
+	})
+
+	store := newFakeUIDStore("uid-one")
+	entities, err := c.FetchNew(store)
+	if err != nil {
+		t.Fatalf("FetchNew: %v", err)
+	}
+
+	if len(entities) != 1 {
+		t.Fatalf("FetchNew returned %d entities, want 1", len(entities))
+	}
+	if !store.Has("uid-two") {
+		t.Errorf("expected uid-two to be recorded in the store after a successful fetch")
+	}
+}